@@ -0,0 +1,229 @@
+// Package canonicalizer implements RFC 8785 JSON Canonicalization Scheme (JCS).
+//
+// It is used to produce a deterministic byte representation of a JSON value
+// so that chaincode can independently recompute hashes (e.g. Sidetree
+// suffix/delta hashes) over client-supplied JSON instead of trusting the
+// client's own serialization.
+package canonicalizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Canonicalize parses the given JSON document and returns its RFC 8785
+// canonical form: object keys sorted by UTF-16 code unit order, numbers
+// serialized per ECMA-262 7.1.12.1, and strings/whitespace normalized to the
+// single canonical encoding.
+func Canonicalize(jsonDoc []byte) ([]byte, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(jsonDoc)))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("canonicalizer: invalid JSON: %w", err)
+	}
+
+	var buf strings.Builder
+	if err := encodeValue(&buf, value); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+// CanonicalizeValue canonicalizes an already-decoded JSON value (as produced
+// by json.Unmarshal into interface{}, ideally with json.Number for numbers).
+func CanonicalizeValue(value interface{}) ([]byte, error) {
+	var buf strings.Builder
+	if err := encodeValue(&buf, value); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+func encodeValue(buf *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		return encodeNumber(buf, v)
+	case float64:
+		return encodeNumber(buf, json.Number(strconv.FormatFloat(v, 'g', -1, 64)))
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		return encodeArray(buf, v)
+	case map[string]interface{}:
+		return encodeObject(buf, v)
+	default:
+		return fmt.Errorf("canonicalizer: unsupported JSON value type %T", value)
+	}
+	return nil
+}
+
+func encodeArray(buf *strings.Builder, arr []interface{}) error {
+	buf.WriteByte('[')
+	for i, item := range arr {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeValue(buf, item); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func encodeObject(buf *strings.Builder, obj map[string]interface{}) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less16(keys[i], keys[j])
+	})
+
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodeString(buf, k)
+		buf.WriteByte(':')
+		if err := encodeValue(buf, obj[k]); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// less16 orders strings by UTF-16 code unit sequence, as required by RFC
+// 8785 section 3.2.3.
+func less16(a, b string) bool {
+	au := utf16Units(a)
+	bu := utf16Units(b)
+	for i := 0; i < len(au) && i < len(bu); i++ {
+		if au[i] != bu[i] {
+			return au[i] < bu[i]
+		}
+	}
+	return len(au) < len(bu)
+}
+
+func utf16Units(s string) []uint16 {
+	units := make([]uint16, 0, len(s))
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			units = append(units, uint16(0xD800+(r>>10)), uint16(0xDC00+(r&0x3FF)))
+		} else {
+			units = append(units, uint16(r))
+		}
+	}
+	return units
+}
+
+// encodeString writes s as a JSON string using the minimal escaping required
+// by RFC 8785 section 3.2.2.2: control characters, quote and backslash are
+// escaped; everything else (including U+2028/U+2029) is emitted as-is.
+func encodeString(buf *strings.Builder, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// encodeNumber serializes a JSON number per ECMA-262 7.1.12.1 (the algorithm
+// behind JavaScript's Number#toString), which is what RFC 8785 mandates.
+func encodeNumber(buf *strings.Builder, n json.Number) error {
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("canonicalizer: invalid number %q: %w", n, err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("canonicalizer: number %q is not representable in JSON", n)
+	}
+
+	if f == 0 {
+		if math.Signbit(f) {
+			buf.WriteString("0")
+		} else {
+			buf.WriteString("0")
+		}
+		return nil
+	}
+
+	// Integers that fit exactly in a float64 are rendered without exponent
+	// or fractional part, matching JS Number#toString for integral values.
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		buf.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		return nil
+	}
+
+	// Like JS Number#toString, use plain decimal notation for magnitudes in
+	// [1e-6, 1e21) and exponent notation outside that range.
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	repr := strconv.FormatFloat(f, format, -1, 64)
+	repr = fixupExponent(repr)
+	buf.WriteString(repr)
+	return nil
+}
+
+// fixupExponent rewrites Go's exponent notation (e.g. "1e+21", "1e-07") into
+// the form used by JS Number#toString ("1e+21", "1e-7").
+func fixupExponent(s string) string {
+	idx := strings.IndexAny(s, "eE")
+	if idx < 0 {
+		return s
+	}
+	mantissa, exp := s[:idx], s[idx+1:]
+	sign := "+"
+	if len(exp) > 0 && (exp[0] == '+' || exp[0] == '-') {
+		if exp[0] == '-' {
+			sign = "-"
+		}
+		exp = exp[1:]
+	}
+	exp = strings.TrimLeft(exp, "0")
+	if exp == "" {
+		exp = "0"
+	}
+	return mantissa + "e" + sign + exp
+}
@@ -1,8 +1,7 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -26,35 +25,31 @@ func (t *DIDChaincode) GetVersion() string {
 // DIDDocument represents a DID document structure
 // Enhanced for two-organization network (CompanyA & CompanyB)
 type DIDDocument struct {
-	DID         string    `json:"did"`
-	LongFormDID string    `json:"longFormDid"`
-	Document    string    `json:"document"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	Version     int       `json:"version"`
-	Recovered   bool      `json:"recovered,omitempty"`
-	RecoveredAt time.Time `json:"recoveredAt,omitempty"`
-	UpdateKey   string    `json:"updateKey,omitempty"`   // Public key for updates
-	RecoveryKey string    `json:"recoveryKey,omitempty"` // Public key for recovery
-	CreatedBy   string    `json:"createdBy,omitempty"`   // Organization that created the DID
-	EndorsedBy  []string  `json:"endorsedBy,omitempty"`  // Organizations that endorsed operations
+	DID                string    `json:"did"`
+	LongFormDID        string    `json:"longFormDid"`
+	Document           string    `json:"document"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+	Version            int       `json:"version"`
+	Recovered          bool      `json:"recovered,omitempty"`
+	RecoveredAt        time.Time `json:"recoveredAt,omitempty"`
+	UpdateKey          string    `json:"updateKey,omitempty"`          // JWK of the key that must sign the next update
+	RecoveryKey        string    `json:"recoveryKey,omitempty"`        // JWK of the key that must sign the next recovery
+	UpdateCommitment   string    `json:"updateCommitment,omitempty"`   // Commitment the next update's reveal value must hash to
+	RecoveryCommitment string    `json:"recoveryCommitment,omitempty"` // Commitment the next recovery's reveal value must hash to
+	CreatedBy          string    `json:"createdBy,omitempty"`          // Organization that created the DID
+	EndorsedBy         []string  `json:"endorsedBy,omitempty"`         // Organizations that endorsed operations
+	Deactivated        bool      `json:"deactivated,omitempty"`
+	DeactivatedAt      time.Time `json:"deactivatedAt,omitempty"`
+	PurgeAfter         time.Time `json:"purgeAfter,omitempty"`      // Record becomes eligible for GC via a range scan once past this time
+	OperationHashes    []string  `json:"operationHashes,omitempty"` // Leaf hashes of every operation applied, oldest first
+	OperationsRoot     string    `json:"operationsRoot,omitempty"`  // Merkle root over OperationHashes
+	Controllers        []string  `json:"controllers,omitempty"`     // Controller DIDs extracted from Document, for CouchDB queries
 }
 
-// validateSignature performs basic signature validation (simplified for demo)
-func (t *DIDChaincode) validateSignature(message, signature, publicKey string) bool {
-	// Simplified validation: check if signature contains hash of message + key
-	// In production, use proper cryptographic signature verification
-	if signature == "" || publicKey == "" {
-		return false
-	}
-	
-	// Create expected signature hash
-	hash := sha256.Sum256([]byte(message + publicKey))
-	expectedSig := hex.EncodeToString(hash[:])
-	
-	// Check if provided signature matches or contains expected pattern
-	return strings.Contains(signature, expectedSig[:16]) // First 16 chars for demo
-}
+// deactivatedRecordTTL is how long a tombstoned DID record is retained
+// before PurgeAfter makes it eligible for garbage collection.
+const deactivatedRecordTTL = 365 * 24 * time.Hour
 
 // Init is called during chaincode instantiation
 func (t *DIDChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
@@ -64,20 +59,36 @@ func (t *DIDChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
 // Invoke is called per transaction on the chaincode
 func (t *DIDChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 	function, args := stub.GetFunctionAndParameters()
-	
+
 	switch function {
 	case "InitLedger":
-		return t.initLedger(stub)
+		return t.initLedger(stub, args)
 	case "CreateDID":
 		return t.createDID(stub, args)
 	case "UpdateDID":
 		return t.updateDID(stub, args)
 	case "RecoverDID":
 		return t.recoverDID(stub, args)
+	case "DeactivateDID":
+		return t.deactivateDID(stub, args)
+	case "SetDIDEndorsementPolicy":
+		return t.setDIDEndorsementPolicy(stub, args)
 	case "GetDID":
 		return t.getDID(stub, args)
+	case "ResolveDID":
+		return t.resolveDID(stub, args)
+	case "GetDIDHistory":
+		return t.getDIDHistory(stub, args)
 	case "ListDIDs":
 		return t.listDIDs(stub)
+	case "QueryDIDsByOrg":
+		return t.queryDIDsByOrg(stub, args)
+	case "QueryDIDsByController":
+		return t.queryDIDsByController(stub, args)
+	case "QueryDIDsByKey":
+		return t.queryDIDsByKey(stub, args)
+	case "QueryDIDs":
+		return t.queryDIDs(stub, args)
 	case "GetVersion":
 		return t.getVersion(stub)
 	case "GetNetworkInfo":
@@ -87,10 +98,27 @@ func (t *DIDChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 	}
 }
 
-// initLedger initializes the ledger
-func (t *DIDChaincode) initLedger(stub shim.ChaincodeStubInterface) peer.Response {
-	fmt.Println("DID Chaincode v1.2x initialized for two-organization network")
-	fmt.Println("Supporting CompanyA (m-FQEEX22AZNEGDDJL4WCQP6KYHU) and CompanyB (m-JLGL2ZEX6BDIXIEFYD4RJVZSTI)")
+// initLedger initializes the ledger. It optionally accepts a single JSON
+// array argument of {"mspId", "displayName"} entries, e.g.
+// `[{"mspId":"Org1MSP","displayName":"CompanyA"}]`, which seeds the
+// org-registry key driving organization name resolution and controller
+// endorsement policies on any network, rather than a hard-coded MSP list.
+func (t *DIDChaincode) initLedger(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) > 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 0-1: [orgRegistryJSON]")
+	}
+
+	var entries []orgRegistryEntry
+	if len(args) == 1 && args[0] != "" {
+		if err := json.Unmarshal([]byte(args[0]), &entries); err != nil {
+			return shim.Error(fmt.Sprintf("Invalid org registry JSON: %s", err))
+		}
+	}
+	if err := saveOrgRegistry(stub, entries); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Printf("DID Chaincode v1.2x initialized with %d registered organization(s)\n", len(entries))
 	return shim.Success([]byte("DID Chaincode v1.2x initialized successfully"))
 }
 
@@ -103,7 +131,7 @@ func (t *DIDChaincode) createDID(stub shim.ChaincodeStubInterface, args []string
 	did := args[0]
 	longFormDid := args[1]
 	documentJSON := args[2]
-	
+
 	// Optional keys for signature validation
 	var updateKey, recoveryKey string
 	if len(args) >= 4 {
@@ -122,6 +150,18 @@ func (t *DIDChaincode) createDID(stub shim.ChaincodeStubInterface, args []string
 		return shim.Error(fmt.Sprintf("DID %s already exists", did))
 	}
 
+	// If the caller supplied a Sidetree long-form DID, cryptographically
+	// verify that the short-form DID is actually derived from the embedded
+	// create operation rather than trusting the client-supplied suffix.
+	if longFormDid != "" {
+		if err := verifyLongFormDID(did, longFormDid); err != nil {
+			return shim.Error(fmt.Sprintf("Long-form DID verification failed: %s", err))
+		}
+		if err := verifyLongFormDocument(documentJSON, longFormDid); err != nil {
+			return shim.Error(fmt.Sprintf("Long-form DID document verification failed: %s", err))
+		}
+	}
+
 	// Get deterministic timestamp from transaction
 	txTimestamp, err := stub.GetTxTimestamp()
 	if err != nil {
@@ -130,31 +170,49 @@ func (t *DIDChaincode) createDID(stub shim.ChaincodeStubInterface, args []string
 	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
 	// Get creator organization info
-	creator, err := stub.GetCreator()
+	_, createdBy, err := callerOrg(stub)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
+		return shim.Error(fmt.Sprintf("Failed to resolve creator organization: %s", err))
+	}
+
+	// Derive the reveal commitments for the first update/recovery operation
+	// from the supplied JWKs, rather than trusting a client-supplied value.
+	var updateCommitment, recoveryCommitment string
+	if updateKey != "" {
+		updateCommitment, err = commitmentFromJWK(updateKey)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invalid updateKey JWK: %s", err))
+		}
 	}
-	
-	// Extract organization from creator (simplified)
-	createdBy := "unknown"
-	if strings.Contains(string(creator), "m-FQEEX22AZNEGDDJL4WCQP6KYHU") {
-		createdBy = "CompanyA"
-	} else if strings.Contains(string(creator), "m-JLGL2ZEX6BDIXIEFYD4RJVZSTI") {
-		createdBy = "CompanyB"
+	if recoveryKey != "" {
+		recoveryCommitment, err = commitmentFromJWK(recoveryKey)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invalid recoveryKey JWK: %s", err))
+		}
 	}
 
-	// Create DID document with organization tracking
 	didDocument := DIDDocument{
-		DID:         did,
-		LongFormDID: longFormDid,
-		Document:    documentJSON,
-		CreatedAt:   txTime,
-		UpdatedAt:   txTime,
-		Version:     1,
-		UpdateKey:   updateKey,
-		RecoveryKey: recoveryKey,
-		CreatedBy:   createdBy,
-		EndorsedBy:  []string{createdBy},
+		DID:                did,
+		LongFormDID:        longFormDid,
+		Document:           documentJSON,
+		Controllers:        extractControllerList(documentJSON),
+		CreatedAt:          txTime,
+		UpdatedAt:          txTime,
+		Version:            1,
+		UpdateKey:          updateKey,
+		RecoveryKey:        recoveryKey,
+		UpdateCommitment:   updateCommitment,
+		RecoveryCommitment: recoveryCommitment,
+		CreatedBy:          createdBy,
+		EndorsedBy:         []string{createdBy},
+	}
+
+	createDelta, err := json.Marshal(map[string]string{"document": documentJSON, "longFormDid": longFormDid})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := recordOperation(stub, DIDDocument{}, &didDocument, "create", createDelta, ""); err != nil {
+		return shim.Error(err.Error())
 	}
 
 	didJSON, err := json.Marshal(didDocument)
@@ -167,26 +225,34 @@ func (t *DIDChaincode) createDID(stub shim.ChaincodeStubInterface, args []string
 		return shim.Error(err.Error())
 	}
 
+	if err := applyControllerEndorsement(stub, did, documentJSON); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	return shim.Success(didJSON)
 }
 
-// updateDID updates a DID Document
+// updateDID applies a signed update operation to a DID Document
 func (t *DIDChaincode) updateDID(stub shim.ChaincodeStubInterface, args []string) peer.Response {
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3: did, updatedDocumentJSON, operationSignature")
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1: operationRequestJSON")
 	}
 
-	did := args[0]
-	updatedDocumentJSON := args[1]
-	operationSignature := args[2]
+	req, err := parseOperationRequest(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if req.Operation != "update" {
+		return shim.Error(fmt.Sprintf("Expected operation \"update\", got %q", req.Operation))
+	}
 
 	// Get existing DID document
-	didJSON, err := stub.GetState(did)
+	didJSON, err := stub.GetState(req.DID)
 	if err != nil {
 		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
 	}
 	if didJSON == nil {
-		return shim.Error(fmt.Sprintf("DID %s does not exist", did))
+		return shim.Error(fmt.Sprintf("DID %s does not exist", req.DID))
 	}
 
 	var existingDID DIDDocument
@@ -194,6 +260,24 @@ func (t *DIDChaincode) updateDID(stub shim.ChaincodeStubInterface, args []string
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	if existingDID.Deactivated {
+		return shim.Error(fmt.Sprintf("DID %s is deactivated and can no longer be updated", req.DID))
+	}
+
+	if err := checkReveal(req.RevealValue, existingDID.UpdateCommitment); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyDeltaHash(req); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyOperationJWS(req, existingDID.UpdateKey); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid operation signature for update: %s", err))
+	}
+
+	var delta updateDelta
+	if err := json.Unmarshal(req.Delta, &delta); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid update delta: %s", err))
+	}
 
 	// Get deterministic timestamp from transaction
 	txTimestamp, err := stub.GetTxTimestamp()
@@ -202,32 +286,21 @@ func (t *DIDChaincode) updateDID(stub shim.ChaincodeStubInterface, args []string
 	}
 	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// Validate signature if updateKey exists
-	if existingDID.UpdateKey != "" {
-		message := fmt.Sprintf("%s:%s:%d", did, updatedDocumentJSON, existingDID.Version+1)
-		if !t.validateSignature(message, operationSignature, existingDID.UpdateKey) {
-			return shim.Error("Invalid operation signature for update")
-		}
-	}
-
 	// Get updater organization info
-	creator, err := stub.GetCreator()
+	_, updatedBy, err := callerOrg(stub)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
-	}
-	
-	updatedBy := "unknown"
-	if strings.Contains(string(creator), "m-FQEEX22AZNEGDDJL4WCQP6KYHU") {
-		updatedBy = "CompanyA"
-	} else if strings.Contains(string(creator), "m-JLGL2ZEX6BDIXIEFYD4RJVZSTI") {
-		updatedBy = "CompanyB"
+		return shim.Error(fmt.Sprintf("Failed to resolve updater organization: %s", err))
 	}
 
-	// Update DID document with endorsement tracking
-	existingDID.Document = updatedDocumentJSON
+	// Update DID document, rotating the update key and commitment
+	priorDID := existingDID
+	existingDID.Document = delta.Document
+	existingDID.Controllers = extractControllerList(delta.Document)
 	existingDID.UpdatedAt = txTime
 	existingDID.Version++
-	
+	existingDID.UpdateKey = string(delta.NewUpdateKey)
+	existingDID.UpdateCommitment = delta.NewUpdateCommitment
+
 	// Add to endorsed by list if not already present
 	found := false
 	for _, org := range existingDID.EndorsedBy {
@@ -240,36 +313,49 @@ func (t *DIDChaincode) updateDID(stub shim.ChaincodeStubInterface, args []string
 		existingDID.EndorsedBy = append(existingDID.EndorsedBy, updatedBy)
 	}
 
+	if err := recordOperation(stub, priorDID, &existingDID, "update", req.Delta, req.JWS); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	updatedJSON, err := json.Marshal(existingDID)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	err = stub.PutState(did, updatedJSON)
+	err = stub.PutState(req.DID, updatedJSON)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
+	if err := applyControllerEndorsement(stub, req.DID, existingDID.Document); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	return shim.Success(updatedJSON)
 }
 
-// recoverDID recovers a lost DID
+// recoverDID applies a signed recovery operation, rotating both the update
+// and recovery key sets
 func (t *DIDChaincode) recoverDID(stub shim.ChaincodeStubInterface, args []string) peer.Response {
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3: did, newDocumentJSON, recoverySignature")
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1: operationRequestJSON")
 	}
 
-	did := args[0]
-	newDocumentJSON := args[1]
-	recoverySignature := args[2]
+	req, err := parseOperationRequest(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if req.Operation != "recover" {
+		return shim.Error(fmt.Sprintf("Expected operation \"recover\", got %q", req.Operation))
+	}
 
 	// Get existing DID document
-	didJSON, err := stub.GetState(did)
+	didJSON, err := stub.GetState(req.DID)
 	if err != nil {
 		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
 	}
 	if didJSON == nil {
-		return shim.Error(fmt.Sprintf("DID %s does not exist", did))
+		return shim.Error(fmt.Sprintf("DID %s does not exist", req.DID))
 	}
 
 	var existingDID DIDDocument
@@ -277,6 +363,24 @@ func (t *DIDChaincode) recoverDID(stub shim.ChaincodeStubInterface, args []strin
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	if existingDID.Deactivated {
+		return shim.Error(fmt.Sprintf("DID %s is deactivated and can no longer be recovered", req.DID))
+	}
+
+	if err := checkReveal(req.RevealValue, existingDID.RecoveryCommitment); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyDeltaHash(req); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyOperationJWS(req, existingDID.RecoveryKey); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid recovery signature: %s", err))
+	}
+
+	var delta recoverDelta
+	if err := json.Unmarshal(req.Delta, &delta); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid recover delta: %s", err))
+	}
 
 	// Get deterministic timestamp from transaction
 	txTimestamp, err := stub.GetTxTimestamp()
@@ -285,34 +389,25 @@ func (t *DIDChaincode) recoverDID(stub shim.ChaincodeStubInterface, args []strin
 	}
 	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// Validate recovery signature if recoveryKey exists
-	if existingDID.RecoveryKey != "" {
-		message := fmt.Sprintf("%s:recovery:%s:%d", did, newDocumentJSON, existingDID.Version+1)
-		if !t.validateSignature(message, recoverySignature, existingDID.RecoveryKey) {
-			return shim.Error("Invalid recovery signature")
-		}
-	}
-
 	// Get recoverer organization info
-	creator, err := stub.GetCreator()
+	_, recoveredBy, err := callerOrg(stub)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
-	}
-	
-	recoveredBy := "unknown"
-	if strings.Contains(string(creator), "m-FQEEX22AZNEGDDJL4WCQP6KYHU") {
-		recoveredBy = "CompanyA"
-	} else if strings.Contains(string(creator), "m-JLGL2ZEX6BDIXIEFYD4RJVZSTI") {
-		recoveredBy = "CompanyB"
+		return shim.Error(fmt.Sprintf("Failed to resolve recoverer organization: %s", err))
 	}
 
-	// Recover DID document with endorsement tracking
-	existingDID.Document = newDocumentJSON
+	// Recover DID document, rotating both key sets and their commitments
+	priorDID := existingDID
+	existingDID.Document = delta.Document
+	existingDID.Controllers = extractControllerList(delta.Document)
 	existingDID.UpdatedAt = txTime
 	existingDID.Version++
 	existingDID.Recovered = true
 	existingDID.RecoveredAt = txTime
-	
+	existingDID.UpdateKey = string(delta.NewUpdateKey)
+	existingDID.UpdateCommitment = delta.NewUpdateCommitment
+	existingDID.RecoveryKey = string(delta.NewRecoveryKey)
+	existingDID.RecoveryCommitment = delta.NewRecoveryCommitment
+
 	// Add to endorsed by list if not already present
 	found := false
 	for _, org := range existingDID.EndorsedBy {
@@ -325,19 +420,187 @@ func (t *DIDChaincode) recoverDID(stub shim.ChaincodeStubInterface, args []strin
 		existingDID.EndorsedBy = append(existingDID.EndorsedBy, recoveredBy)
 	}
 
+	if err := recordOperation(stub, priorDID, &existingDID, "recover", req.Delta, req.JWS); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	recoveredJSON, err := json.Marshal(existingDID)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	err = stub.PutState(did, recoveredJSON)
+	err = stub.PutState(req.DID, recoveredJSON)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
+	if err := applyControllerEndorsement(stub, req.DID, existingDID.Document); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	return shim.Success(recoveredJSON)
 }
 
+// deactivateDID permanently tombstones a DID: it requires a recovery-key
+// signature like recoverDID, but clears the document and key material
+// instead of rotating it, and marks the record so future UpdateDID/RecoverDID
+// calls are rejected.
+func (t *DIDChaincode) deactivateDID(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1: operationRequestJSON")
+	}
+
+	req, err := parseOperationRequest(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if req.Operation != "deactivate" {
+		return shim.Error(fmt.Sprintf("Expected operation \"deactivate\", got %q", req.Operation))
+	}
+
+	didJSON, err := stub.GetState(req.DID)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
+	}
+	if didJSON == nil {
+		return shim.Error(fmt.Sprintf("DID %s does not exist", req.DID))
+	}
+
+	var existingDID DIDDocument
+	err = json.Unmarshal(didJSON, &existingDID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if existingDID.Deactivated {
+		return shim.Error(fmt.Sprintf("DID %s is already deactivated", req.DID))
+	}
+
+	if err := checkReveal(req.RevealValue, existingDID.RecoveryCommitment); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyDeltaHash(req); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyOperationJWS(req, existingDID.RecoveryKey); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid deactivation signature: %s", err))
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get transaction timestamp: %s", err))
+	}
+	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	priorDID := existingDID
+	existingDID.Document = ""
+	existingDID.UpdateKey = ""
+	existingDID.RecoveryKey = ""
+	existingDID.UpdateCommitment = ""
+	existingDID.RecoveryCommitment = ""
+	existingDID.UpdatedAt = txTime
+	existingDID.Version++
+	existingDID.Deactivated = true
+	existingDID.DeactivatedAt = txTime
+	existingDID.PurgeAfter = txTime.Add(deactivatedRecordTTL)
+
+	if err := recordOperation(stub, priorDID, &existingDID, "deactivate", req.Delta, req.JWS); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	deactivatedJSON, err := json.Marshal(existingDID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.PutState(req.DID, deactivatedJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return shim.Success(deactivatedJSON)
+}
+
+// setDIDEndorsementPolicy overrides the key-level endorsement policy that
+// applyControllerEndorsement derives from a DID document's "controllers"
+// field, e.g. to require additional or different organizations than those
+// named in the document. Like deactivateDID, it is guarded by a recovery-key
+// signature rather than changing the document itself.
+func (t *DIDChaincode) setDIDEndorsementPolicy(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1: operationRequestJSON")
+	}
+
+	req, err := parseOperationRequest(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if req.Operation != "setEndorsementPolicy" {
+		return shim.Error(fmt.Sprintf("Expected operation \"setEndorsementPolicy\", got %q", req.Operation))
+	}
+
+	didJSON, err := stub.GetState(req.DID)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
+	}
+	if didJSON == nil {
+		return shim.Error(fmt.Sprintf("DID %s does not exist", req.DID))
+	}
+
+	var existingDID DIDDocument
+	if err := json.Unmarshal(didJSON, &existingDID); err != nil {
+		return shim.Error(err.Error())
+	}
+	if existingDID.Deactivated {
+		return shim.Error(fmt.Sprintf("DID %s is deactivated and can no longer have its endorsement policy changed", req.DID))
+	}
+
+	if err := checkReveal(req.RevealValue, existingDID.RecoveryCommitment); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyDeltaHash(req); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := verifyOperationJWS(req, existingDID.RecoveryKey); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid operation signature for setEndorsementPolicy: %s", err))
+	}
+
+	var delta setEndorsementPolicyDelta
+	if err := json.Unmarshal(req.Delta, &delta); err != nil {
+		return shim.Error(fmt.Sprintf("Invalid setEndorsementPolicy delta: %s", err))
+	}
+	policyBytes, err := base64.StdEncoding.DecodeString(delta.Policy)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Invalid policy encoding: %s", err))
+	}
+	if err := stub.SetStateValidationParameter(req.DID, policyBytes); err != nil {
+		return shim.Error(fmt.Sprintf("Failed to set endorsement policy: %s", err))
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get transaction timestamp: %s", err))
+	}
+	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+
+	priorDID := existingDID
+	existingDID.UpdatedAt = txTime
+	existingDID.Version++
+
+	if err := recordOperation(stub, priorDID, &existingDID, "setEndorsementPolicy", req.Delta, req.JWS); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	updatedJSON, err := json.Marshal(existingDID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := stub.PutState(req.DID, updatedJSON); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return shim.Success(updatedJSON)
+}
+
 // getDID retrieves a DID Document
 func (t *DIDChaincode) getDID(stub shim.ChaincodeStubInterface, args []string) peer.Response {
 	if len(args) != 1 {
@@ -387,48 +650,60 @@ func (t *DIDChaincode) listDIDs(stub shim.ChaincodeStubInterface) peer.Response
 	return shim.Success(didsJSON)
 }
 
-// getVersion returns the chaincode version
+// getVersion returns the chaincode version and the organizations currently
+// registered in the org-registry, so this stays accurate on any network
+// InitLedger was seeded for instead of naming a fixed two-org deployment.
 func (t *DIDChaincode) getVersion(stub shim.ChaincodeStubInterface) peer.Response {
+	entries, err := loadOrgRegistry(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	orgNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		orgNames = append(orgNames, fmt.Sprintf("%s (%s)", entry.DisplayName, entry.MSPID))
+	}
+
 	version := map[string]string{
-		"version": "1.2x",
-		"description": "DID Chaincode for two-organization network with full DID requirements",
-		"organizations": "CompanyA (m-FQEEX22AZNEGDDJL4WCQP6KYHU), CompanyB (m-JLGL2ZEX6BDIXIEFYD4RJVZSTI)",
+		"version":       "1.2x",
+		"description":   "DID Chaincode with full DID requirements",
+		"organizations": strings.Join(orgNames, ", "),
 	}
-	
+
 	versionJSON, err := json.Marshal(version)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
-	
+
 	return shim.Success(versionJSON)
 }
 
-// getNetworkInfo returns network information
+// getNetworkInfo returns the organizations registered in the org-registry
+// and the endorsement scheme driven by it, rather than a fixed network's
+// MSP IDs and peer endpoints.
 func (t *DIDChaincode) getNetworkInfo(stub shim.ChaincodeStubInterface) peer.Response {
+	entries, err := loadOrgRegistry(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	organizations := make([]map[string]string, 0, len(entries))
+	for _, entry := range entries {
+		organizations = append(organizations, map[string]string{
+			"name":   entry.DisplayName,
+			"msp_id": entry.MSPID,
+		})
+	}
+
 	networkInfo := map[string]interface{}{
-		"chaincode_version": "1.2x",
-		"network_type": "two-organization",
-		"organizations": []map[string]string{
-			{
-				"name": "CompanyA",
-				"msp_id": "m-FQEEX22AZNEGDDJL4WCQP6KYHU",
-				"peer": "nd-lhf6gjm2mrg2bkl4k2fycpwrd4.m-fqeex22aznegddjl4wcqp6kyhu.n-lhs7rblbt5drppe2pfry3il3yu.managedblockchain.us-east-1.amazonaws.com:30003",
-			},
-			{
-				"name": "CompanyB", 
-				"msp_id": "m-JLGL2ZEX6BDIXIEFYD4RJVZSTI",
-				"peer": "nd-7sfv4dmoobf77guclpma7za2je.m-jlgl2zex6bdixiefyd4rjvzsti.n-lhs7rblbt5drppe2pfry3il3yu.managedblockchain.us-east-1.amazonaws.com:30006",
-			},
-		},
-		"channel": "mychannel",
-		"endorsement_policy": "MAJORITY (requires both organizations)",
-	}
-	
+		"chaincode_version":  "1.2x",
+		"organizations":      organizations,
+		"endorsement_policy": fmt.Sprintf("per-DID: requires endorsement from each organization named in the document's \"controllers\" field (%d organization(s) registered)", len(entries)),
+	}
+
 	infoJSON, err := json.Marshal(networkInfo)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
-	
+
 	return shim.Success(infoJSON)
 }
 
@@ -437,4 +712,4 @@ func main() {
 	if err := shim.Start(new(DIDChaincode)); err != nil {
 		fmt.Printf("Error starting DID chaincode: %s", err)
 	}
-}
\ No newline at end of file
+}
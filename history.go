@@ -0,0 +1,302 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+
+	"did-chaincode/canonicalizer"
+)
+
+// historyObjectType is the composite-key object type under which per-DID
+// operation history is stored: history~<did>~<versionPadded>.
+const historyObjectType = "history"
+
+// versionKeyWidth is the zero-padding width applied to a version number when
+// building a history composite key, so that Fabric's lexicographic range
+// scans over the key return versions in numeric order.
+const versionKeyWidth = 20
+
+// didHistoryEntry records one operation applied to a DID: the full prior
+// document (the state before this operation), the operation's own delta and
+// signature, and the leaf hash anchored into OperationsRoot.
+type didHistoryEntry struct {
+	Version       int             `json:"version"`
+	Operation     string          `json:"operation"`
+	PriorDocument DIDDocument     `json:"priorDocument"`
+	Delta         json.RawMessage `json:"delta,omitempty"`
+	Signature     string          `json:"signature,omitempty"`
+	OperationHash string          `json:"operationHash"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// merkleProofStep is one sibling hash on the path from a leaf to the root.
+type merkleProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"` // "left" or "right", the sibling's side
+}
+
+// merkleInclusionProof lets an off-chain verifier confirm that a given
+// version's operation hash is anchored in OperationsRoot without trusting a
+// single peer.
+type merkleInclusionProof struct {
+	Version  int               `json:"version"`
+	LeafHash string            `json:"leafHash"`
+	Siblings []merkleProofStep `json:"siblings"`
+	Root     string            `json:"root"`
+}
+
+// didHistoryResponse is the payload returned by GetDIDHistory.
+type didHistoryResponse struct {
+	DID            string                `json:"did"`
+	Entries        []didHistoryEntry     `json:"entries"`
+	OperationsRoot string                `json:"operationsRoot,omitempty"`
+	Proof          *merkleInclusionProof `json:"proof,omitempty"`
+}
+
+// computeOperationHash hashes the JCS-canonicalized operation (did, the
+// operation's ordinal version, its kind, delta and signature) with SHA-256,
+// producing the leaf anchored into the DID's Merkle tree.
+func computeOperationHash(did, operation string, version int, delta json.RawMessage, signature string) (string, error) {
+	if delta == nil {
+		delta = json.RawMessage("null")
+	}
+	payload := struct {
+		DID       string          `json:"did"`
+		Operation string          `json:"operation"`
+		Version   int             `json:"version"`
+		Delta     json.RawMessage `json:"delta"`
+		Signature string          `json:"signature"`
+	}{did, operation, version, delta, signature}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal operation for hashing: %w", err)
+	}
+	canonical, err := canonicalizer.Canonicalize(payloadJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize operation: %w", err)
+	}
+	hash := sha256.Sum256(canonical)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// merkleRoot computes the standard binary Merkle root over leaves (each the
+// raw bytes of a SHA-256 digest): duplicate the last leaf when a level is
+// odd, and hash SHA-256 of the concatenated children up to the root.
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	level := leaves
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			left := level[i]
+			right := left
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			h := sha256.Sum256(append(append([]byte{}, left...), right...))
+			next = append(next, h[:])
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// merkleProof builds the inclusion proof (sibling hashes from leaf to root)
+// for the leaf at index, using the same duplicate-last-leaf rule as
+// merkleRoot.
+func merkleProof(leaves [][]byte, index int) ([]merkleProofStep, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("version index %d out of range for %d operations", index, len(leaves))
+	}
+	level := leaves
+	idx := index
+	var steps []merkleProofStep
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			left := level[i]
+			right := left
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			if i == idx {
+				steps = append(steps, merkleProofStep{Hash: hex.EncodeToString(right), Position: "right"})
+			} else if i+1 == idx {
+				steps = append(steps, merkleProofStep{Hash: hex.EncodeToString(left), Position: "left"})
+			}
+			h := sha256.Sum256(append(append([]byte{}, left...), right...))
+			next = append(next, h[:])
+		}
+		idx = idx / 2
+		level = next
+	}
+	return steps, nil
+}
+
+// decodeLeaves hex-decodes the stored operation hashes into raw digest bytes
+// for Merkle tree construction.
+func decodeLeaves(operationHashes []string) ([][]byte, error) {
+	leaves := make([][]byte, len(operationHashes))
+	for i, h := range operationHashes {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stored operation hash: %w", err)
+		}
+		leaves[i] = b
+	}
+	return leaves, nil
+}
+
+// historyKey builds the history~<did>~<versionPadded> composite key.
+func historyKey(stub shim.ChaincodeStubInterface, did string, version int) (string, error) {
+	return stub.CreateCompositeKey(historyObjectType, []string{did, fmt.Sprintf("%0*d", versionKeyWidth, version)})
+}
+
+// recordOperation appends an operation's hash to newDID's OperationHashes,
+// recomputes newDID's OperationsRoot over them, and writes a durable history~<did>~<version> entry capturing priorDID (the
+// zero value for the create operation), the operation's delta and signature.
+func recordOperation(stub shim.ChaincodeStubInterface, priorDID DIDDocument, newDID *DIDDocument, operation string, delta json.RawMessage, signature string) error {
+	opHash, err := computeOperationHash(newDID.DID, operation, newDID.Version, delta, signature)
+	if err != nil {
+		return err
+	}
+	newDID.OperationHashes = append(newDID.OperationHashes, opHash)
+
+	leaves, err := decodeLeaves(newDID.OperationHashes)
+	if err != nil {
+		return err
+	}
+	newDID.OperationsRoot = hex.EncodeToString(merkleRoot(leaves))
+
+	entry := didHistoryEntry{
+		Version:       newDID.Version,
+		Operation:     operation,
+		PriorDocument: priorDID,
+		Delta:         delta,
+		Signature:     signature,
+		OperationHash: opHash,
+		Timestamp:     newDID.UpdatedAt,
+	}
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	key, err := historyKey(stub, newDID.DID, newDID.Version)
+	if err != nil {
+		return fmt.Errorf("failed to build history key: %w", err)
+	}
+	return stub.PutState(key, entryJSON)
+}
+
+// getDIDHistory returns the ordered slice of operations applied to a DID,
+// optionally restricted to [fromVersion, toVersion] and optionally including
+// a Merkle inclusion proof for a requested version.
+func (t *DIDChaincode) getDIDHistory(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 || len(args) > 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 1-4: did, [fromVersion], [toVersion], [proofVersion]")
+	}
+	did := args[0]
+
+	fromVersion := 1
+	toVersion := -1 // -1 means "no upper bound"
+	proofVersion := 0
+	var err error
+	if len(args) >= 2 && args[1] != "" {
+		fromVersion, err = strconv.Atoi(args[1])
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invalid fromVersion: %s", err))
+		}
+	}
+	if len(args) >= 3 && args[2] != "" {
+		toVersion, err = strconv.Atoi(args[2])
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invalid toVersion: %s", err))
+		}
+	}
+	if len(args) >= 4 && args[3] != "" {
+		proofVersion, err = strconv.Atoi(args[3])
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invalid proofVersion: %s", err))
+		}
+	}
+
+	didJSON, err := stub.GetState(did)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
+	}
+	if didJSON == nil {
+		return shim.Error(fmt.Sprintf("DID %s does not exist", did))
+	}
+	var current DIDDocument
+	if err := json.Unmarshal(didJSON, &current); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(historyObjectType, []string{did})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer iterator.Close()
+
+	var entries []didHistoryEntry
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var entry didHistoryEntry
+		if err := json.Unmarshal(result.Value, &entry); err != nil {
+			return shim.Error(err.Error())
+		}
+		if entry.Version < fromVersion {
+			continue
+		}
+		if toVersion != -1 && entry.Version > toVersion {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	response := didHistoryResponse{
+		DID:            did,
+		Entries:        entries,
+		OperationsRoot: current.OperationsRoot,
+	}
+
+	if proofVersion > 0 {
+		if proofVersion > len(current.OperationHashes) {
+			return shim.Error(fmt.Sprintf("DID %s has no operation at version %d", did, proofVersion))
+		}
+		leaves, err := decodeLeaves(current.OperationHashes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		siblings, err := merkleProof(leaves, proofVersion-1)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		response.Proof = &merkleInclusionProof{
+			Version:  proofVersion,
+			LeafHash: current.OperationHashes[proofVersion-1],
+			Siblings: siblings,
+			Root:     current.OperationsRoot,
+		}
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(responseJSON)
+}
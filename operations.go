@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+
+	"did-chaincode/canonicalizer"
+)
+
+// jwk is a minimal JSON Web Key representation covering the two key types
+// this chaincode accepts: Ed25519 ("OKP"/"Ed25519") and secp256k1 ("EC").
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// operationRequest is the signed envelope accepted by updateDID and
+// recoverDID, modeled on Sidetree update/recover/deactivate operations.
+type operationRequest struct {
+	DID         string          `json:"did"`
+	Operation   string          `json:"operation"`
+	Delta       json.RawMessage `json:"delta"`
+	DeltaHash   string          `json:"deltaHash"`
+	RevealValue string          `json:"revealValue"`
+	JWS         string          `json:"jws"`
+}
+
+// jwsSignedPayload is the canonical payload an operationRequest's JWS signs
+// over: the operation's identity and delta commitment, so the signature is
+// stable regardless of how large the delta itself is.
+type jwsSignedPayload struct {
+	DID         string `json:"did"`
+	Operation   string `json:"operation"`
+	DeltaHash   string `json:"deltaHash"`
+	RevealValue string `json:"revealValue"`
+}
+
+// jwsHeader is the minimal detached-JWS header this chaincode understands.
+type jwsHeader struct {
+	Alg string `json:"alg"`
+}
+
+// updateDelta is the delta payload for an "update" operationRequest: the new
+// document content plus the key/commitment rotated in for the operation
+// after this one.
+type updateDelta struct {
+	Document            string          `json:"document"`
+	NewUpdateKey        json.RawMessage `json:"newUpdateKey"`
+	NewUpdateCommitment string          `json:"newUpdateCommitment"`
+}
+
+// recoverDelta is the delta payload for a "recover" operationRequest: like
+// updateDelta, but rotates both the update key and the recovery key since
+// recovery supersedes the entire key set.
+type recoverDelta struct {
+	Document              string          `json:"document"`
+	NewUpdateKey          json.RawMessage `json:"newUpdateKey"`
+	NewUpdateCommitment   string          `json:"newUpdateCommitment"`
+	NewRecoveryKey        json.RawMessage `json:"newRecoveryKey"`
+	NewRecoveryCommitment string          `json:"newRecoveryCommitment"`
+}
+
+// setEndorsementPolicyDelta is the delta payload for a "setEndorsementPolicy"
+// operationRequest: a base64-encoded, already-serialized Fabric key-level
+// endorsement policy (as produced by statebased.KeyEndorsementPolicy.Policy),
+// applied verbatim to the DID's ledger key.
+type setEndorsementPolicyDelta struct {
+	Policy string `json:"policy"`
+}
+
+// parseOperationRequest decodes an OperationRequest JSON envelope.
+func parseOperationRequest(requestJSON string) (operationRequest, error) {
+	var req operationRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return req, fmt.Errorf("invalid operation request JSON: %w", err)
+	}
+	return req, nil
+}
+
+// commitmentFromJWK derives the reveal commitment for a JWK: the SHA-256
+// hash (hex-encoded) of its JCS-canonicalized form.
+func commitmentFromJWK(jwkJSON string) (string, error) {
+	canonical, err := canonicalizer.Canonicalize([]byte(jwkJSON))
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize key: %w", err)
+	}
+	hash := sha256.Sum256(canonical)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// checkReveal verifies that revealValue is the preimage of commitment, per
+// the Sidetree commit-reveal scheme used to rotate update/recovery keys.
+func checkReveal(revealValue, commitment string) error {
+	if commitment == "" {
+		return fmt.Errorf("no commitment on record to check reveal value against")
+	}
+	hash := sha256.Sum256([]byte(revealValue))
+	if hex.EncodeToString(hash[:]) != commitment {
+		return fmt.Errorf("reveal value does not match stored commitment")
+	}
+	return nil
+}
+
+// verifyDeltaHash checks that req.DeltaHash is the multihash of the
+// JCS-canonicalized delta, using the same convention as the suffixData
+// deltaHash check at createDID.
+func verifyDeltaHash(req operationRequest) error {
+	canonicalDelta, err := canonicalizer.Canonicalize(req.Delta)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize delta: %w", err)
+	}
+	if multihashSha256B64URL(canonicalDelta) != req.DeltaHash {
+		return fmt.Errorf("deltaHash does not match JCS hash of delta")
+	}
+	return nil
+}
+
+// verifyOperationJWS verifies req.JWS against storedKeyJWK (the JWK stored
+// on the DID record for the relevant key, i.e. updateKey or recoveryKey).
+// It checks that the JWS payload matches the operation's own did/operation/
+// deltaHash/revealValue fields, then verifies the signature deterministically
+// using the algorithm named in the JWS header: EdDSA (crypto/ed25519) or
+// ES256K (secp256k1 via btcec, which implements RFC 6979 deterministic
+// ECDSA since Go's standard crypto/ecdsa does not support this curve).
+func verifyOperationJWS(req operationRequest, storedKeyJWK string) error {
+	parts := strings.Split(req.JWS, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWS: expected header.payload.signature")
+	}
+	headerB64, payloadB64, sigB64 := parts[0], parts[1], parts[2]
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(headerB64)
+	if err != nil {
+		return fmt.Errorf("invalid JWS header encoding: %w", err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return fmt.Errorf("invalid JWS header JSON: %w", err)
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(payloadB64)
+	if err != nil {
+		return fmt.Errorf("invalid JWS payload encoding: %w", err)
+	}
+	var payload jwsSignedPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return fmt.Errorf("invalid JWS payload JSON: %w", err)
+	}
+	expected := jwsSignedPayload{
+		DID:         req.DID,
+		Operation:   req.Operation,
+		DeltaHash:   req.DeltaHash,
+		RevealValue: req.RevealValue,
+	}
+	if payload != expected {
+		return fmt.Errorf("JWS payload does not match operation request fields")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil {
+		return fmt.Errorf("invalid JWS signature encoding: %w", err)
+	}
+	signingInput := []byte(headerB64 + "." + payloadB64)
+
+	var key jwk
+	if err := json.Unmarshal([]byte(storedKeyJWK), &key); err != nil {
+		return fmt.Errorf("invalid stored key JWK: %w", err)
+	}
+
+	switch header.Alg {
+	case "EdDSA":
+		pubKey, err := jwkToEd25519PublicKey(key)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(pubKey, signingInput, signature) {
+			return fmt.Errorf("EdDSA signature verification failed")
+		}
+	case "ES256K":
+		pubKey, err := jwkToSecp256k1PublicKey(key)
+		if err != nil {
+			return err
+		}
+		if len(signature) != 64 {
+			return fmt.Errorf("ES256K signature must be 64 bytes (compact r||s)")
+		}
+		var r, s btcec.ModNScalar
+		r.SetByteSlice(signature[:32])
+		s.SetByteSlice(signature[32:])
+		sig := ecdsa.NewSignature(&r, &s)
+		digest := sha256.Sum256(signingInput)
+		if !sig.Verify(digest[:], pubKey) {
+			return fmt.Errorf("ES256K signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported JWS algorithm %q", header.Alg)
+	}
+
+	return nil
+}
+
+// jwkToEd25519PublicKey decodes an OKP/Ed25519 JWK into a standard library
+// Ed25519 public key.
+func jwkToEd25519PublicKey(key jwk) (ed25519.PublicKey, error) {
+	if key.Kty != "OKP" || key.Crv != "Ed25519" {
+		return nil, fmt.Errorf("expected an OKP/Ed25519 JWK, got kty=%q crv=%q", key.Kty, key.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK x value: %w", err)
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key length %d", len(x))
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// jwkToSecp256k1PublicKey decodes an EC/secp256k1 JWK into a btcec public key.
+func jwkToSecp256k1PublicKey(key jwk) (*btcec.PublicKey, error) {
+	if key.Kty != "EC" || key.Crv != "secp256k1" {
+		return nil, fmt.Errorf("expected an EC/secp256k1 JWK, got kty=%q crv=%q", key.Kty, key.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK x value: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK y value: %w", err)
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("invalid secp256k1 coordinate length")
+	}
+	uncompressed := append([]byte{0x04}, append(x, y...)...)
+	pubKey, err := btcec.ParsePubKey(uncompressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+	}
+	return pubKey, nil
+}
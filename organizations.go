@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// orgRegistryKey is the ledger key under which the network's MSP ID ->
+// display name mapping is stored. It is seeded by InitLedger and consulted
+// by every invoke that previously hard-coded MSP IDs.
+const orgRegistryKey = "org-registry"
+
+// orgRegistryEntry maps a channel member's MSP ID to a human-readable
+// organization name, e.g. {"mspId": "Org1MSP", "displayName": "CompanyA"}.
+type orgRegistryEntry struct {
+	MSPID       string `json:"mspId"`
+	DisplayName string `json:"displayName"`
+}
+
+// saveOrgRegistry persists the org registry to state as a JSON array.
+func saveOrgRegistry(stub shim.ChaincodeStubInterface, entries []orgRegistryEntry) error {
+	registryJSON, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(orgRegistryKey, registryJSON)
+}
+
+// loadOrgRegistry reads the org registry from state. Returns an empty slice
+// if InitLedger has not yet seeded one.
+func loadOrgRegistry(stub shim.ChaincodeStubInterface) ([]orgRegistryEntry, error) {
+	registryJSON, err := stub.GetState(orgRegistryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read org registry: %w", err)
+	}
+	if registryJSON == nil {
+		return nil, nil
+	}
+	var entries []orgRegistryEntry
+	if err := json.Unmarshal(registryJSON, &entries); err != nil {
+		return nil, fmt.Errorf("corrupt org registry: %w", err)
+	}
+	return entries, nil
+}
+
+// displayNameForMSP looks up the display name registered for mspID, falling
+// back to the raw MSP ID itself when the registry has no entry for it so the
+// chaincode still functions on networks that never called InitLedger with a
+// registry.
+func displayNameForMSP(entries []orgRegistryEntry, mspID string) string {
+	for _, entry := range entries {
+		if entry.MSPID == mspID {
+			return entry.DisplayName
+		}
+	}
+	return mspID
+}
+
+// mspIDForName resolves a controller name (either an MSP ID or a registered
+// display name) from the org registry back to its MSP ID. Returns "" if no
+// entry matches.
+func mspIDForName(entries []orgRegistryEntry, name string) string {
+	for _, entry := range entries {
+		if entry.MSPID == name || entry.DisplayName == name {
+			return entry.MSPID
+		}
+	}
+	return ""
+}
+
+// callerOrg extracts the MSP ID of the transaction's submitting identity by
+// unmarshalling stub.GetCreator() as a serialized MSP identity, then
+// resolves it to a display name via the org registry.
+func callerOrg(stub shim.ChaincodeStubInterface) (mspID string, displayName string, err error) {
+	creatorBytes, err := stub.GetCreator()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get creator: %w", err)
+	}
+
+	var identity msp.SerializedIdentity
+	if err := proto.Unmarshal(creatorBytes, &identity); err != nil {
+		return "", "", fmt.Errorf("failed to parse creator identity: %w", err)
+	}
+	if identity.Mspid == "" {
+		return "", "", fmt.Errorf("creator identity has no MSP ID")
+	}
+
+	// Parsing the X.509 subject is not required to resolve the MSP ID, but
+	// surfaces a clearer error if the creator identity is malformed, and
+	// gives future invokes (e.g. attribute-based access control) a verified
+	// certificate to inspect.
+	if block, _ := pem.Decode(identity.IdBytes); block != nil {
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", "", fmt.Errorf("failed to parse creator certificate: %w", err)
+		}
+	}
+
+	entries, err := loadOrgRegistry(stub)
+	if err != nil {
+		return "", "", err
+	}
+	return identity.Mspid, displayNameForMSP(entries, identity.Mspid), nil
+}
+
+// extractControllers pulls the top-level "controllers" field out of a DID
+// document, accepting either the single-string or string-array form. Returns
+// nil if absent or malformed.
+func extractControllers(documentJSON string) []string {
+	var doc struct {
+		Controllers json.RawMessage `json:"controllers"`
+	}
+	if err := json.Unmarshal([]byte(documentJSON), &doc); err != nil || doc.Controllers == nil {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(doc.Controllers, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	if err := json.Unmarshal(doc.Controllers, &list); err == nil {
+		return list
+	}
+	return nil
+}
+
+// applyControllerEndorsement sets a key-level endorsement policy on did
+// requiring a MEMBER-role endorsement from every organization named in the
+// document's "controllers" field. Controller entries that don't resolve to
+// a known MSP ID are ignored; if none resolve, the channel default
+// endorsement policy is left in place.
+func applyControllerEndorsement(stub shim.ChaincodeStubInterface, did string, documentJSON string) error {
+	controllers := extractControllers(documentJSON)
+	if len(controllers) == 0 {
+		return nil
+	}
+
+	entries, err := loadOrgRegistry(stub)
+	if err != nil {
+		return err
+	}
+
+	var mspIDs []string
+	for _, controller := range controllers {
+		if mspID := mspIDForName(entries, controller); mspID != "" {
+			mspIDs = append(mspIDs, mspID)
+		}
+	}
+	if len(mspIDs) == 0 {
+		return nil
+	}
+
+	policy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return fmt.Errorf("failed to build endorsement policy: %w", err)
+	}
+	if err := policy.AddOrgs(statebased.RoleTypeMember, mspIDs...); err != nil {
+		return fmt.Errorf("failed to add orgs to endorsement policy: %w", err)
+	}
+	policyBytes, err := policy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to serialize endorsement policy: %w", err)
+	}
+	return stub.SetStateValidationParameter(did, policyBytes)
+}
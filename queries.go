@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// defaultQueryPageSize is used when a query invoke isn't given an explicit
+// page size.
+const defaultQueryPageSize = 10
+
+// queryResultsResponse is the payload returned by every CouchDB-backed query
+// invoke, carrying the bookmark clients need to fetch the next page.
+type queryResultsResponse struct {
+	Records             []DIDDocument `json:"records"`
+	Bookmark            string        `json:"bookmark"`
+	FetchedRecordsCount int32         `json:"fetchedRecordsCount"`
+}
+
+// extractControllerList pulls the top-level "controller" field out of a DID
+// document, accepting either the single-string or string-array form allowed
+// by the W3C DID Core spec, and returns every controller named rather than
+// just the first so none are invisible to QueryDIDsByController. Returns nil
+// if absent or malformed.
+func extractControllerList(documentJSON string) []string {
+	var doc struct {
+		Controller json.RawMessage `json:"controller"`
+	}
+	if err := json.Unmarshal([]byte(documentJSON), &doc); err != nil || doc.Controller == nil {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(doc.Controller, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	if err := json.Unmarshal(doc.Controller, &list); err == nil && len(list) > 0 {
+		return list
+	}
+	return nil
+}
+
+// parsePageSize parses an optional pageSize argument, defaulting to
+// defaultQueryPageSize when absent or zero.
+func parsePageSize(arg string) (int32, error) {
+	if arg == "" {
+		return defaultQueryPageSize, nil
+	}
+	pageSize, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid page size: %w", err)
+	}
+	if pageSize == 0 {
+		return defaultQueryPageSize, nil
+	}
+	return int32(pageSize), nil
+}
+
+// runPaginatedQuery executes a Mango selector against the CouchDB state
+// database with pagination, returning the matching DID records and the
+// bookmark for the next page.
+func runPaginatedQuery(stub shim.ChaincodeStubInterface, query string, pageSize int32, bookmark string) peer.Response {
+	iterator, metadata, err := stub.GetQueryResultWithPagination(query, pageSize, bookmark)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("CouchDB query failed: %s", err))
+	}
+	defer iterator.Close()
+
+	var records []DIDDocument
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var did DIDDocument
+		if err := json.Unmarshal(result.Value, &did); err != nil {
+			return shim.Error(err.Error())
+		}
+		records = append(records, did)
+	}
+
+	response := queryResultsResponse{
+		Records:             records,
+		Bookmark:            metadata.GetBookmark(),
+		FetchedRecordsCount: metadata.GetFetchedRecordsCount(),
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(responseJSON)
+}
+
+// queryDIDsByOrg returns DIDs created by the given organization.
+func (t *DIDChaincode) queryDIDsByOrg(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 1-3: org, [pageSize], [bookmark]")
+	}
+	pageSize, err := parsePageSize(argOrEmpty(args, 1))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	selector := fmt.Sprintf(`{"selector":{"createdBy":%q}}`, args[0])
+	return runPaginatedQuery(stub, selector, pageSize, argOrEmpty(args, 2))
+}
+
+// queryDIDsByController returns DIDs whose document names controllerDID as
+// one of (possibly several) controllers.
+func (t *DIDChaincode) queryDIDsByController(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 1-3: controllerDID, [pageSize], [bookmark]")
+	}
+	pageSize, err := parsePageSize(argOrEmpty(args, 1))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	selector := fmt.Sprintf(`{"selector":{"controllers":{"$elemMatch":{"$eq":%q}}}}`, args[0])
+	return runPaginatedQuery(stub, selector, pageSize, argOrEmpty(args, 2))
+}
+
+// queryDIDsByKey returns DIDs whose stored updateKey JWK contains
+// pubKeyBase64url, the base64url-encoded key material stored in the JWK's
+// "x" (or "y") coordinate field - updateKey is never hex-encoded, so a
+// hex-encoded key will not match.
+func (t *DIDChaincode) queryDIDsByKey(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 1-3: pubKeyBase64url, [pageSize], [bookmark]")
+	}
+	pageSize, err := parsePageSize(argOrEmpty(args, 1))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	selector := fmt.Sprintf(`{"selector":{"updateKey":{"$regex":%q}}}`, args[0])
+	return runPaginatedQuery(stub, selector, pageSize, argOrEmpty(args, 2))
+}
+
+// queryDIDs runs a caller-supplied Mango selector directly.
+func (t *DIDChaincode) queryDIDs(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 1-3: mangoQuery, [pageSize], [bookmark]")
+	}
+	pageSize, err := parsePageSize(argOrEmpty(args, 1))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return runPaginatedQuery(stub, args[0], pageSize, argOrEmpty(args, 2))
+}
+
+// argOrEmpty returns args[i] if present, else "".
+func argOrEmpty(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
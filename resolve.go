@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// didResolutionContext is the @context every DID Resolution Result is
+// tagged with, per https://w3id.org/did-resolution/v1.
+const didResolutionContext = "https://w3id.org/did-resolution/v1"
+
+// didDocumentContentType is the media type returned for a successfully
+// resolved DID document.
+const didDocumentContentType = "application/did+ld+json"
+
+// supportedDIDMethod is the only DID method this chaincode anchors and can
+// resolve; any other method segment yields a methodNotSupported error.
+const supportedDIDMethod = "fabric"
+
+const (
+	resolutionErrorInvalidDid         = "invalidDid"
+	resolutionErrorNotFound           = "notFound"
+	resolutionErrorDeactivated        = "deactivated"
+	resolutionErrorMethodNotSupported = "methodNotSupported"
+)
+
+// didResolutionResult is a W3C DID Core "DID Resolution Result":
+// https://www.w3.org/TR/did-core/#did-resolution.
+type didResolutionResult struct {
+	Context               string                `json:"@context"`
+	DIDDocument           json.RawMessage       `json:"didDocument,omitempty"`
+	DIDResolutionMetadata didResolutionMetadata `json:"didResolutionMetadata"`
+	DIDDocumentMetadata   didDocumentMetadata   `json:"didDocumentMetadata"`
+}
+
+// didResolutionMetadata describes the resolution process itself, as opposed
+// to the resolved document.
+type didResolutionMetadata struct {
+	ContentType string `json:"contentType,omitempty"`
+	Retrieved   string `json:"retrieved,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// didDocumentMetadata describes the resolved document's provenance on the
+// ledger, populated from the DIDDocument record for anchored DIDs, or left
+// mostly empty (with method.published=false) for unpublished long-form DIDs.
+type didDocumentMetadata struct {
+	Created      string             `json:"created,omitempty"`
+	Updated      string             `json:"updated,omitempty"`
+	VersionID    string             `json:"versionId,omitempty"`
+	Deactivated  bool               `json:"deactivated,omitempty"`
+	CanonicalID  string             `json:"canonicalId,omitempty"`
+	EquivalentID []string           `json:"equivalentId,omitempty"`
+	Method       *didMethodMetadata `json:"method,omitempty"`
+}
+
+// didMethodMetadata carries Sidetree's method-specific "published" flag,
+// distinguishing an anchored DID from one resolved purely from its long-form
+// initial state.
+type didMethodMetadata struct {
+	Published bool `json:"published"`
+}
+
+// resolveDID implements a W3C DID Core compliant ResolveDID invoke. It
+// accepts either a short-form DID (did:<method>:<suffix>) or a Sidetree
+// long-form DID (did:<method>:<suffix>:<base64url(initialState)>). Error
+// conditions are reported via didResolutionMetadata.error rather than
+// failing the invoke, matching DID resolver semantics.
+func (t *DIDChaincode) resolveDID(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1: did")
+	}
+
+	retrieved, err := currentResolutionTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	shortFormDID, longFormDid, err := splitDIDInput(args[0])
+	if err != nil {
+		return shim.Success(mustMarshalResolutionResult(resolutionError(retrieved, resolutionErrorInvalidDid)))
+	}
+	if method := didMethod(shortFormDID); method != supportedDIDMethod {
+		return shim.Success(mustMarshalResolutionResult(resolutionError(retrieved, resolutionErrorMethodNotSupported)))
+	}
+
+	didJSON, err := stub.GetState(shortFormDID)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get DID: %s", err))
+	}
+	if didJSON != nil {
+		return shim.Success(mustMarshalResolutionResult(resolveAnchoredDID(didJSON, shortFormDID, retrieved)))
+	}
+
+	if longFormDid == "" {
+		return shim.Success(mustMarshalResolutionResult(resolutionError(retrieved, resolutionErrorNotFound)))
+	}
+	result, err := resolveUnpublishedDID(shortFormDID, longFormDid, retrieved)
+	if err != nil {
+		return shim.Success(mustMarshalResolutionResult(resolutionError(retrieved, resolutionErrorInvalidDid)))
+	}
+	return shim.Success(mustMarshalResolutionResult(result))
+}
+
+// resolveAnchoredDID builds a resolution result from a DIDDocument record
+// already published on the ledger.
+func resolveAnchoredDID(didJSON []byte, shortFormDID string, retrieved string) didResolutionResult {
+	var did DIDDocument
+	if err := json.Unmarshal(didJSON, &did); err != nil {
+		return resolutionError(retrieved, resolutionErrorInvalidDid)
+	}
+
+	metadata := didDocumentMetadata{
+		Updated:     did.UpdatedAt.UTC().Format(time.RFC3339),
+		VersionID:   strconv.Itoa(did.Version),
+		Deactivated: did.Deactivated,
+		Method:      &didMethodMetadata{Published: true},
+	}
+	if did.Deactivated {
+		return didResolutionResult{
+			Context:               didResolutionContext,
+			DIDResolutionMetadata: didResolutionMetadata{Retrieved: retrieved, Error: resolutionErrorDeactivated},
+			DIDDocumentMetadata:   metadata,
+		}
+	}
+
+	// Document is stored verbatim from caller-supplied args with no JSON
+	// validation at create/update/recover time, so a non-JSON value must be
+	// rejected here rather than embedded as json.RawMessage, which would
+	// make json.Marshal fail (and mustMarshalResolutionResult panic).
+	if !json.Valid([]byte(did.Document)) {
+		return resolutionError(retrieved, resolutionErrorInvalidDid)
+	}
+
+	metadata.Created = did.CreatedAt.UTC().Format(time.RFC3339)
+	if did.LongFormDID != "" {
+		metadata.CanonicalID = shortFormDID
+		metadata.EquivalentID = []string{did.LongFormDID}
+	}
+	return didResolutionResult{
+		Context:               didResolutionContext,
+		DIDDocument:           json.RawMessage(did.Document),
+		DIDResolutionMetadata: didResolutionMetadata{ContentType: didDocumentContentType, Retrieved: retrieved},
+		DIDDocumentMetadata:   metadata,
+	}
+}
+
+// resolveUnpublishedDID synthesizes a resolution result for a long-form DID
+// that has not yet been anchored, verifying it the same way createDID does
+// before trusting its embedded document.
+func resolveUnpublishedDID(shortFormDID, longFormDid, retrieved string) (didResolutionResult, error) {
+	if err := verifyLongFormDID(shortFormDID, longFormDid); err != nil {
+		return didResolutionResult{}, err
+	}
+	deltaDocument, err := longFormDeltaDocument(longFormDid)
+	if err != nil {
+		return didResolutionResult{}, err
+	}
+
+	return didResolutionResult{
+		Context:     didResolutionContext,
+		DIDDocument: deltaDocument,
+		DIDResolutionMetadata: didResolutionMetadata{
+			ContentType: didDocumentContentType,
+			Retrieved:   retrieved,
+		},
+		DIDDocumentMetadata: didDocumentMetadata{
+			EquivalentID: []string{longFormDid},
+			Method:       &didMethodMetadata{Published: false},
+		},
+	}, nil
+}
+
+// resolutionError builds a resolution result carrying only an error code, as
+// returned for notFound/invalidDid/deactivated/methodNotSupported.
+func resolutionError(retrieved, code string) didResolutionResult {
+	return didResolutionResult{
+		Context:               didResolutionContext,
+		DIDResolutionMetadata: didResolutionMetadata{Retrieved: retrieved, Error: code},
+		DIDDocumentMetadata:   didDocumentMetadata{},
+	}
+}
+
+// currentResolutionTime returns the transaction's deterministic timestamp
+// formatted as RFC 3339, used as didResolutionMetadata.retrieved.
+func currentResolutionTime(stub shim.ChaincodeStubInterface) (string, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC().Format(time.RFC3339), nil
+}
+
+// splitDIDInput parses a did input that may be either short-form
+// (did:<method>:<suffix>) or Sidetree long-form
+// (did:<method>:<suffix>:<base64url(initialState)>), returning the
+// short-form DID and, if present, the original long-form DID.
+func splitDIDInput(input string) (shortFormDID string, longFormDid string, err error) {
+	parts := strings.Split(input, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", "", fmt.Errorf("malformed DID %q", input)
+	}
+	if parts[0] != "did" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("malformed DID %q", input)
+	}
+	shortFormDID = strings.Join(parts[:3], ":")
+	if len(parts) == 4 {
+		longFormDid = input
+	}
+	return shortFormDID, longFormDid, nil
+}
+
+// didMethod returns the method segment of a short-form DID.
+func didMethod(shortFormDID string) string {
+	parts := strings.Split(shortFormDID, ":")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// mustMarshalResolutionResult marshals a didResolutionResult. Only its
+// DIDDocument field can fail to marshal (when it is not valid JSON), and
+// callers only pass documents that are valid JSON, so a marshal error here
+// indicates a programming mistake.
+func mustMarshalResolutionResult(result didResolutionResult) []byte {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal DID resolution result: %s", err))
+	}
+	return resultJSON
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"did-chaincode/canonicalizer"
+)
+
+// sha2-256 multihash prefix: code 0x12 (sha2-256), length 0x20 (32 bytes),
+// per the multihash spec (https://github.com/multiformats/multihash).
+var multihashSha256Prefix = []byte{0x12, 0x20}
+
+// sidetreeInitialState is the payload embedded in a Sidetree long-form DID,
+// i.e. the base64url segment of
+// did:<method>:<suffix>:<base64url(initialState)>.
+type sidetreeInitialState struct {
+	SuffixData json.RawMessage `json:"suffixData"`
+	Delta      json.RawMessage `json:"delta"`
+}
+
+// sidetreeSuffixData is the subset of suffixData fields this chaincode
+// checks; additional fields (if any) are preserved verbatim in SuffixData
+// for hashing since JCS canonicalizes the whole object, not just these.
+type sidetreeSuffixData struct {
+	DeltaHash          string `json:"deltaHash"`
+	RecoveryCommitment string `json:"recoveryCommitment"`
+}
+
+// multihashSha256B64URL SHA-256 hashes data, wraps it in a multihash header
+// and returns the base64url (no padding) encoding, matching the Sidetree
+// hashing convention used for suffixes and delta hashes.
+func multihashSha256B64URL(data []byte) string {
+	digest := sha256.Sum256(data)
+	mh := make([]byte, 0, len(multihashSha256Prefix)+len(digest))
+	mh = append(mh, multihashSha256Prefix...)
+	mh = append(mh, digest[:]...)
+	return base64.RawURLEncoding.EncodeToString(mh)
+}
+
+// parseLongFormDID splits a Sidetree long-form DID of the form
+// did:<method>:<suffix>:<base64url(initialState)> into its short-form suffix
+// and decoded initial state.
+func parseLongFormDID(longFormDid string) (suffix string, state sidetreeInitialState, err error) {
+	parts := strings.Split(longFormDid, ":")
+	if len(parts) != 4 || parts[0] != "did" {
+		return "", state, fmt.Errorf("malformed long-form DID %q", longFormDid)
+	}
+	suffix = parts[2]
+	encodedState := parts[3]
+
+	stateBytes, err := base64.RawURLEncoding.DecodeString(encodedState)
+	if err != nil {
+		return "", state, fmt.Errorf("invalid base64url initial state: %w", err)
+	}
+	if err := json.Unmarshal(stateBytes, &state); err != nil {
+		return "", state, fmt.Errorf("invalid initial state JSON: %w", err)
+	}
+	return suffix, state, nil
+}
+
+// verifyLongFormDID cryptographically verifies that shortFormDID is actually
+// derived from the create operation embedded in longFormDid, per the
+// Sidetree anchoring algorithm: the short-form suffix must equal the
+// multihash of the JCS-canonicalized suffixData, and suffixData.deltaHash
+// must equal the multihash of the JCS-canonicalized delta.
+func verifyLongFormDID(shortFormDID, longFormDid string) error {
+	expectedSuffix, state, err := parseLongFormDID(longFormDid)
+	if err != nil {
+		return err
+	}
+
+	var suffixData sidetreeSuffixData
+	if err := json.Unmarshal(state.SuffixData, &suffixData); err != nil {
+		return fmt.Errorf("invalid suffixData JSON: %w", err)
+	}
+
+	canonicalSuffixData, err := canonicalizer.Canonicalize(state.SuffixData)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize suffixData: %w", err)
+	}
+	computedSuffix := multihashSha256B64URL(canonicalSuffixData)
+
+	// shortFormDID is did:<method>:<suffix>; only the trailing suffix
+	// component is compared against the recomputed value.
+	didSuffix := shortFormDID
+	if idx := strings.LastIndex(shortFormDID, ":"); idx != -1 {
+		didSuffix = shortFormDID[idx+1:]
+	}
+	if computedSuffix != didSuffix || computedSuffix != expectedSuffix {
+		return fmt.Errorf("long-form DID suffix does not match recomputed suffix from create operation")
+	}
+
+	canonicalDelta, err := canonicalizer.Canonicalize(state.Delta)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize delta: %w", err)
+	}
+	computedDeltaHash := multihashSha256B64URL(canonicalDelta)
+	if computedDeltaHash != suffixData.DeltaHash {
+		return fmt.Errorf("delta hash does not match suffixData.deltaHash")
+	}
+
+	return nil
+}
+
+// longFormDeltaDocument extracts the "document" field embedded in a
+// long-form DID's delta, the same field both createDID and ResolveDID must
+// treat as authoritative for what the long-form DID commits to.
+func longFormDeltaDocument(longFormDid string) (json.RawMessage, error) {
+	_, state, err := parseLongFormDID(longFormDid)
+	if err != nil {
+		return nil, err
+	}
+
+	var delta struct {
+		Document json.RawMessage `json:"document"`
+	}
+	if err := json.Unmarshal(state.Delta, &delta); err != nil {
+		return nil, fmt.Errorf("invalid delta JSON: %w", err)
+	}
+	if delta.Document == nil {
+		return nil, fmt.Errorf("long-form DID delta does not embed a document")
+	}
+	return delta.Document, nil
+}
+
+// verifyLongFormDocument checks that documentJSON is exactly the document
+// committed to by longFormDid's delta (per JCS canonical equality), so a
+// caller cannot anchor a document that diverges from what the long-form DID
+// cryptographically commits to and what ResolveDID would later return for
+// it.
+func verifyLongFormDocument(documentJSON, longFormDid string) error {
+	deltaDocument, err := longFormDeltaDocument(longFormDid)
+	if err != nil {
+		return err
+	}
+
+	canonicalSupplied, err := canonicalizer.Canonicalize([]byte(documentJSON))
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize supplied document: %w", err)
+	}
+	canonicalDelta, err := canonicalizer.Canonicalize(deltaDocument)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize delta document: %w", err)
+	}
+	if string(canonicalSupplied) != string(canonicalDelta) {
+		return fmt.Errorf("supplied document does not match the document committed to by the long-form DID's delta")
+	}
+	return nil
+}